@@ -17,14 +17,28 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"errors"
+	"flag"
 	"log/slog"
 	"net/http"
 	"os"
 	"time"
 
 	"github.com/canonical/mutating-pebble-webhook-rock/pkg/webhook"
+	"github.com/canonical/mutating-pebble-webhook-rock/pkg/webhook/bootstrap"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+var (
+	selfBootstrap     = flag.Bool("self-bootstrap", false, "Generate a self-signed CA and serving certificate, and reconcile the MutatingWebhookConfiguration's caBundle, instead of relying on an external process such as cert-manager.")
+	webhookConfigName = flag.String("webhook-config-name", "mutating-pebble-webhook", "Name of the MutatingWebhookConfiguration to reconcile the caBundle of. Only used with --self-bootstrap.")
+	serviceNamespace  = flag.String("service-namespace", "", "Namespace of the Service fronting this webhook. Only used with --self-bootstrap.")
+	serviceName       = flag.String("service-name", "", "Name of the Service fronting this webhook. Only used with --self-bootstrap.")
+	caSecretName      = flag.String("ca-secret-name", "", "Name of a Secret in --service-namespace used to share the CA identity across replicas. If unset, each replica generates its own CA. Only used with --self-bootstrap.")
 )
 
 func initLogger() {
@@ -49,36 +63,133 @@ func ensureFile(path string) {
 }
 
 func main() {
+	flag.Parse()
 	initLogger()
 	slog.Info("Starting mutating-pebble-webhook...")
 
 	cert := "/etc/admission-webhook/tls/tls.crt"
 	key := "/etc/admission-webhook/tls/tls.key"
-	ensureFile(cert)
-	ensureFile(key)
 
-	tlsCertif, err := tls.LoadX509KeyPair(cert, key)
-	if err != nil {
-		slog.Error("Encountered error while loading certificate.", "error", err)
-		panic(err)
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	var readiness webhook.ReadinessChecker
+	if *selfBootstrap {
+		getCertificate, checker := setupSelfBootstrap(cert, key)
+		tlsConfig.GetCertificate = getCertificate
+		readiness = checker
+	} else {
+		ensureFile(cert)
+		ensureFile(key)
+
+		tlsCertif, err := tls.LoadX509KeyPair(cert, key)
+		if err != nil {
+			slog.Error("Encountered error while loading certificate.", "error", err)
+			panic(err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{tlsCertif}
+
+		checker, err := webhook.NewStaticCertReadinessChecker(tlsCertif)
+		if err != nil {
+			slog.Error("Encountered error while setting up readiness check.", "error", err)
+			panic(err)
+		}
+		readiness = checker
 	}
 
 	server := &http.Server{
 		Addr:              ":8443",
 		ReadHeaderTimeout: 5 * time.Second,
-		TLSConfig: &tls.Config{
-			Certificates: []tls.Certificate{tlsCertif},
-			MinVersion:   tls.VersionTLS12,
-		},
+		TLSConfig:         tlsConfig,
 	}
 
-	http.HandleFunc("/add-pebble-mount", webhook.ServeAddPebbleMount)
+	builder := webhook.NewBuilder().
+		Register("/add-pebble-mount", webhook.NewAddPebbleMountMutator(buildKubeClient())).
+		Register("/validate-pebble-mount", webhook.ValidatePebbleMountValidator)
+
+	http.Handle("/", builder.Handler())
 	http.HandleFunc("/healthz", webhook.ServeHealthz)
+	http.HandleFunc("/readyz", webhook.ServeReadyz(readiness))
+	http.Handle("/metrics", promhttp.Handler())
 
 	slog.Info("Listening connections...")
-	err = server.ListenAndServeTLS("", "")
+	err := server.ListenAndServeTLS("", "")
 	if err != nil {
 		slog.Error("Encountered error.", "error", err)
 		panic(err)
 	}
 }
+
+// buildKubeClient returns an in-cluster Kubernetes client, used to look up
+// the pebble.rocks.canonical.com/default-inject label on a Pod's namespace.
+// It returns nil (rather than panicking) when no in-cluster config is
+// available, e.g. when running outside a cluster, in which case that lookup
+// is simply skipped.
+func buildKubeClient() kubernetes.Interface {
+	restCfg, err := rest.InClusterConfig()
+	if err != nil {
+		slog.Warn("Not running in-cluster; namespace default-inject labels will not be honored.", "error", err)
+		return nil
+	}
+
+	client, err := kubernetes.NewForConfig(restCfg)
+	if err != nil {
+		slog.Error("Encountered error while creating Kubernetes client.", "error", err)
+		panic(err)
+	}
+
+	return client
+}
+
+// setupSelfBootstrap starts the bootstrap subsystem, blocking until the
+// first certificate has been issued and the MutatingWebhookConfiguration
+// has been reconciled, then runs its rotation loop in the background for
+// the lifetime of the process. The returned Bootstrapper also serves as the
+// readiness check, since its readiness requires the caBundle to have caught
+// up with its CA.
+func setupSelfBootstrap(cert, key string) (func(*tls.ClientHelloInfo) (*tls.Certificate, error), webhook.ReadinessChecker) {
+	if *serviceName == "" || *serviceNamespace == "" {
+		slog.Error("--self-bootstrap requires --service-name and --service-namespace.")
+		panic("missing required self-bootstrap flags")
+	}
+
+	b, err := bootstrap.New(bootstrap.Config{
+		CertFile:          cert,
+		KeyFile:           key,
+		WebhookConfigName: *webhookConfigName,
+		ServiceName:       *serviceName,
+		ServiceNamespace:  *serviceNamespace,
+		CASecretName:      *caSecretName,
+	})
+	if err != nil {
+		slog.Error("Encountered error while setting up self-bootstrap.", "error", err)
+		panic(err)
+	}
+
+	ctx := context.Background()
+	done := make(chan error, 1)
+	go func() { done <- b.Run(ctx) }()
+
+	// b.Run performs the initial issuance and reconcile synchronously before
+	// entering its rotation loop, but since it runs in a goroutine we need our
+	// own signal that they're done before handing GetCertificate to the HTTPS
+	// server. Poll the bootstrapper until it reports ready (a certificate has
+	// been issued and the caBundle matches its CA) or the initial reconcile
+	// fails.
+	for {
+		if err := b.Ready(ctx); err == nil {
+			break
+		}
+		select {
+		case err := <-done:
+			if err != nil {
+				slog.Error("Encountered error during initial webhook bootstrap.", "error", err)
+				panic(err)
+			}
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+
+	return b.GetCertificate, b
+}
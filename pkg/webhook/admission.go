@@ -0,0 +1,81 @@
+/*
+Copyright 2024 Canonical, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+
+	"github.com/appscode/jsonpatch"
+	v1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Request is the part of an AdmissionReview handlers care about.
+type Request struct {
+	*v1.AdmissionRequest
+}
+
+// Response is what an Admitter returns. A Builder translates it into the
+// AdmissionResponse half of the AdmissionReview sent back to the API server.
+type Response struct {
+	// Allowed is whether the request should be admitted.
+	Allowed bool
+
+	// Patches, if non-empty, are applied to the object on Allowed responses.
+	Patches []jsonpatch.Operation
+
+	// Result carries the reason for the decision. For denials, Message is
+	// surfaced to the requesting user; it's optional on allowed responses.
+	Result *metav1.Status
+}
+
+// Admitter decides the outcome of a single admission request. Mutator and
+// Validator are the two Admitter implementations handlers are expected to
+// use; most new handlers shouldn't need to implement Admitter directly.
+type Admitter interface {
+	Handle(ctx context.Context, req Request) Response
+}
+
+// Decoder decodes the object embedded in an admission request into a
+// concrete Kubernetes API type. Implementations are expected to check
+// req.Resource before decoding req.Object.Raw, so a webhook that's miswired
+// to the wrong resource fails with a clear error instead of decoding garbage
+// into a zero-value object.
+type Decoder func(req Request) (runtime.Object, error)
+
+// Allowed is a Response that admits the request unchanged.
+func Allowed() Response {
+	return Response{Allowed: true}
+}
+
+// Denied is a Response that rejects the request with the given reason.
+func Denied(reason string) Response {
+	return Response{
+		Allowed: false,
+		Result:  &metav1.Status{Message: reason},
+	}
+}
+
+// errored is a Response used when a handler can't even evaluate the
+// request, as opposed to evaluating it and denying it.
+func errored(err error) Response {
+	return Response{
+		Allowed: false,
+		Result:  &metav1.Status{Message: err.Error()},
+	}
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2024 Canonical, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"encoding/json"
+	"log/slog"
+
+	v1 "k8s.io/api/admission/v1"
+)
+
+// podAuditSummary is decoded, best-effort, from the admission request's raw
+// object so the audit log can carry a pod's identity and size without
+// coupling the audit logger to a concrete API type.
+type podAuditSummary struct {
+	Metadata struct {
+		Name         string `json:"name"`
+		GenerateName string `json:"generateName"`
+	} `json:"metadata"`
+	Spec struct {
+		Containers []json.RawMessage `json:"containers"`
+	} `json:"spec"`
+}
+
+// logAdmissionAudit writes a single structured INFO line per admission
+// decision, so operators can correlate webhook decisions with downstream
+// Pod failures without needing to crank up debug logging.
+func logAdmissionAudit(logger *slog.Logger, request *v1.AdmissionRequest, response *v1.AdmissionResponse, patchCount int) {
+	var summary podAuditSummary
+	// Best-effort: a decode failure here shouldn't affect the admission
+	// decision or obscure it from the audit log, so the relevant fields are
+	// just left blank.
+	_ = json.Unmarshal(request.Object.Raw, &summary)
+
+	logger.Info("Admission decision.",
+		"uid", request.UID,
+		"namespace", request.Namespace,
+		"name", summary.Metadata.Name,
+		"generateName", summary.Metadata.GenerateName,
+		"containers", len(summary.Spec.Containers),
+		"patches", patchCount,
+		"allowed", response.Allowed,
+	)
+}
@@ -0,0 +1,276 @@
+/*
+Copyright 2024 Canonical, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package bootstrap lets the webhook provision its own serving certificate
+// and MutatingWebhookConfiguration CA bundle, instead of requiring an
+// external process such as cert-manager to do so.
+package bootstrap
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// rotationThreshold is how far from expiry a leaf certificate must be
+// before it gets re-issued.
+const rotationThreshold = 30 * 24 * time.Hour
+
+// Config configures the self-bootstrap subsystem.
+type Config struct {
+	// CertFile and KeyFile are where the serving certificate and key are
+	// (re)written. They should match whatever path the HTTPS server is
+	// configured to read from.
+	CertFile string
+	KeyFile  string
+
+	// WebhookConfigName is the name of the MutatingWebhookConfiguration
+	// whose caBundle this process keeps in sync with its own CA.
+	WebhookConfigName string
+
+	// ServiceName and ServiceNamespace identify the in-cluster Service
+	// fronting this webhook, used both for the leaf certificate's DNS
+	// names and to find the relevant entries in WebhookConfigName.
+	ServiceName      string
+	ServiceNamespace string
+
+	// CASecretName, if non-empty, enables Secret-backed mode: the CA
+	// certificate and key are stored in this Secret (in ServiceNamespace)
+	// so that multiple replicas share one CA identity instead of each
+	// minting its own. If empty, each replica generates its own CA, held in
+	// memory only; it isn't written to disk, so a restarted replica mints a
+	// new one.
+	CASecretName string
+
+	// ReconcileInterval is how often the rotation/reconcile loop runs.
+	// Defaults to 1 hour if zero.
+	ReconcileInterval time.Duration
+}
+
+// Bootstrapper owns the CA and serving certificate lifecycle: initial
+// issuance, periodic rotation, persisting the serving cert to disk, and
+// keeping a MutatingWebhookConfiguration's caBundle in sync.
+type Bootstrapper struct {
+	cfg    Config
+	client kubernetes.Interface
+
+	mu  sync.RWMutex
+	ca  *caKeyPair
+	cur *tls.Certificate
+}
+
+// New creates a Bootstrapper. It uses the in-cluster Kubernetes config, so
+// it only works when running inside a Pod with an appropriately bound
+// ServiceAccount (get/update on mutatingwebhookconfigurations, and
+// get/create on secrets in ServiceNamespace if CASecretName is set).
+func New(cfg Config) (*Bootstrapper, error) {
+	if cfg.ReconcileInterval == 0 {
+		cfg.ReconcileInterval = time.Hour
+	}
+
+	restCfg, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load in-cluster config: %w", err)
+	}
+
+	client, err := kubernetes.NewForConfig(restCfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kubernetes client: %w", err)
+	}
+
+	return &Bootstrapper{cfg: cfg, client: client}, nil
+}
+
+// Run performs the initial certificate issuance and webhook reconciliation,
+// then blocks, re-checking for rotation every ReconcileInterval until ctx is
+// cancelled. If the initial reconciliation fails, Run returns its error
+// without entering the loop; errors from later iterations are logged. See
+// setupSelfBootstrap in cmd/main.go, which runs it in a goroutine and waits
+// for Ready before serving.
+func (b *Bootstrapper) Run(ctx context.Context) error {
+	if err := b.reconcileOnce(ctx); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(b.cfg.ReconcileInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := b.reconcileOnce(ctx); err != nil {
+				slog.Error("Encountered error while reconciling webhook bootstrap state.", "error", err)
+			}
+		}
+	}
+}
+
+// reconcileOnce ensures the CA identity exists, re-issues the leaf
+// certificate if it's within rotationThreshold of expiry (or doesn't exist
+// yet), and pushes the current CA bundle to the MutatingWebhookConfiguration.
+func (b *Bootstrapper) reconcileOnce(ctx context.Context) error {
+	ca, err := b.ensureCA(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to ensure CA: %w", err)
+	}
+
+	if err := b.ensureLeafCertificate(ca); err != nil {
+		return fmt.Errorf("failed to ensure leaf certificate: %w", err)
+	}
+
+	if err := reconcileWebhookCABundle(ctx, b.client, b.cfg.WebhookConfigName, b.cfg.ServiceName, b.cfg.ServiceNamespace, ca.certPEM); err != nil {
+		return fmt.Errorf("failed to reconcile MutatingWebhookConfiguration: %w", err)
+	}
+
+	return nil
+}
+
+func (b *Bootstrapper) ensureCA(ctx context.Context) (*caKeyPair, error) {
+	b.mu.RLock()
+	if b.ca != nil {
+		ca := b.ca
+		b.mu.RUnlock()
+		return ca, nil
+	}
+	b.mu.RUnlock()
+
+	commonName := fmt.Sprintf("%s.%s CA", b.cfg.ServiceName, b.cfg.ServiceNamespace)
+
+	var ca *caKeyPair
+	var err error
+	if b.cfg.CASecretName != "" {
+		ca, err = loadOrCreateCASecret(ctx, b.client, b.cfg.ServiceNamespace, b.cfg.CASecretName, commonName)
+	} else {
+		ca, err = generateCA(commonName)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	b.mu.Lock()
+	b.ca = ca
+	b.mu.Unlock()
+
+	return ca, nil
+}
+
+func (b *Bootstrapper) ensureLeafCertificate(ca *caKeyPair) error {
+	b.mu.RLock()
+	cur := b.cur
+	b.mu.RUnlock()
+
+	if cur != nil {
+		rotate, err := needsRotation(cur, rotationThreshold)
+		if err != nil {
+			return err
+		}
+		if !rotate {
+			return nil
+		}
+		slog.Info("Leaf certificate is nearing expiry, rotating.")
+	}
+
+	certPEM, keyPEM, err := issueLeafCertificate(ca, b.cfg.ServiceName, b.cfg.ServiceNamespace)
+	if err != nil {
+		return fmt.Errorf("failed to issue leaf certificate: %w", err)
+	}
+
+	if err := writeCertFilesLocked(b.cfg.CertFile, b.cfg.KeyFile, certPEM, keyPEM); err != nil {
+		return err
+	}
+
+	tlsCert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return fmt.Errorf("failed to load generated certificate: %w", err)
+	}
+
+	b.mu.Lock()
+	b.cur = &tlsCert
+	b.mu.Unlock()
+
+	return nil
+}
+
+// GetCertificate is suitable for use as tls.Config.GetCertificate, so the
+// HTTPS server always serves the current certificate, including across
+// rotations, without needing to be restarted.
+func (b *Bootstrapper) GetCertificate(_ *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.cur == nil {
+		return nil, fmt.Errorf("bootstrap: no certificate has been issued yet")
+	}
+	return b.cur, nil
+}
+
+// CABundleMatches reports whether the MutatingWebhookConfiguration's current
+// caBundle matches our in-memory CA, used by the readiness probe.
+func (b *Bootstrapper) CABundleMatches(ctx context.Context) (bool, error) {
+	b.mu.RLock()
+	ca := b.ca
+	b.mu.RUnlock()
+
+	if ca == nil {
+		return false, nil
+	}
+
+	bundle, err := currentCABundle(ctx, b.client, b.cfg.WebhookConfigName, b.cfg.ServiceName, b.cfg.ServiceNamespace)
+	if err != nil {
+		return false, err
+	}
+
+	return string(bundle) == string(ca.certPEM), nil
+}
+
+// Ready implements webhook.ReadinessChecker: the bootstrapper is ready once
+// it has issued a leaf certificate that isn't close to expiring, and the
+// MutatingWebhookConfiguration's caBundle has caught up with our CA.
+func (b *Bootstrapper) Ready(ctx context.Context) error {
+	b.mu.RLock()
+	cur := b.cur
+	b.mu.RUnlock()
+
+	if cur == nil {
+		return fmt.Errorf("no certificate has been issued yet")
+	}
+
+	rotate, err := needsRotation(cur, rotationThreshold)
+	if err != nil {
+		return err
+	}
+	if rotate {
+		return fmt.Errorf("leaf certificate is within %s of expiring and hasn't rotated yet", rotationThreshold)
+	}
+
+	matches, err := b.CABundleMatches(ctx)
+	if err != nil {
+		return err
+	}
+	if !matches {
+		return fmt.Errorf("MutatingWebhookConfiguration caBundle does not match the in-memory CA yet")
+	}
+
+	return nil
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2024 Canonical, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bootstrap
+
+import (
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"testing"
+	"time"
+)
+
+func TestGenerateCA(t *testing.T) {
+	ca, err := generateCA("test CA")
+	if err != nil {
+		t.Fatalf("generateCA() returned error: %v", err)
+	}
+
+	if !ca.cert.IsCA {
+		t.Error("generated certificate is not marked as a CA")
+	}
+	if ca.cert.Subject.CommonName != "test CA" {
+		t.Errorf("CommonName = %q, want %q", ca.cert.Subject.CommonName, "test CA")
+	}
+
+	roundTripped, err := parseCA(ca.certPEM, ca.keyPEM)
+	if err != nil {
+		t.Fatalf("parseCA() on generateCA()'s own output returned error: %v", err)
+	}
+	if roundTripped.cert.SerialNumber.Cmp(ca.cert.SerialNumber) != 0 {
+		t.Error("parseCA() did not round-trip the same certificate")
+	}
+}
+
+func TestIssueLeafCertificate(t *testing.T) {
+	ca, err := generateCA("test CA")
+	if err != nil {
+		t.Fatalf("generateCA() returned error: %v", err)
+	}
+
+	certPEM, keyPEM, err := issueLeafCertificate(ca, "my-webhook", "my-namespace")
+	if err != nil {
+		t.Fatalf("issueLeafCertificate() returned error: %v", err)
+	}
+
+	tlsCert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatalf("tls.X509KeyPair() on issued leaf certificate returned error: %v", err)
+	}
+
+	leaf, err := x509.ParseCertificate(tlsCert.Certificate[0])
+	if err != nil {
+		t.Fatalf("x509.ParseCertificate() on issued leaf certificate returned error: %v", err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	if _, err := leaf.Verify(x509.VerifyOptions{
+		DNSName: "my-webhook.my-namespace.svc",
+		Roots:   pool,
+	}); err != nil {
+		t.Errorf("issued leaf certificate did not verify against its CA: %v", err)
+	}
+
+	wantDNSNames := serviceDNSNames("my-webhook", "my-namespace")
+	if len(leaf.DNSNames) != len(wantDNSNames) {
+		t.Fatalf("DNSNames = %v, want %v", leaf.DNSNames, wantDNSNames)
+	}
+	for i, name := range wantDNSNames {
+		if leaf.DNSNames[i] != name {
+			t.Errorf("DNSNames[%d] = %q, want %q", i, leaf.DNSNames[i], name)
+		}
+	}
+}
+
+func TestNeedsRotation(t *testing.T) {
+	tests := []struct {
+		name      string
+		notAfter  time.Duration
+		threshold time.Duration
+		want      bool
+	}{
+		{
+			name:      "well within validity",
+			notAfter:  30 * 24 * time.Hour,
+			threshold: 7 * 24 * time.Hour,
+			want:      false,
+		},
+		{
+			name:      "within the rotation threshold",
+			notAfter:  6 * 24 * time.Hour,
+			threshold: 7 * 24 * time.Hour,
+			want:      true,
+		},
+		{
+			name:      "already expired",
+			notAfter:  -time.Hour,
+			threshold: 7 * 24 * time.Hour,
+			want:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ca, err := generateCA("test CA")
+			if err != nil {
+				t.Fatalf("generateCA() returned error: %v", err)
+			}
+
+			leaf := &x509.Certificate{
+				SerialNumber: ca.cert.SerialNumber,
+				NotAfter:     time.Now().Add(tt.notAfter),
+			}
+			der, err := x509.CreateCertificate(rand.Reader, leaf, ca.cert, ca.key.Public(), ca.key)
+			if err != nil {
+				t.Fatalf("x509.CreateCertificate() returned error: %v", err)
+			}
+
+			tlsCert := &tls.Certificate{Certificate: [][]byte{der}}
+
+			got, err := needsRotation(tlsCert, tt.threshold)
+			if err != nil {
+				t.Fatalf("needsRotation() returned error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("needsRotation() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2024 Canonical, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bootstrap
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// writeCertFilesLocked writes certPEM/keyPEM to certPath/keyPath, guarding
+// against concurrent replicas of this webhook racing to (re)write the same
+// mounted volume: an flock on a sidecar lockfile serializes writers, and the
+// actual file contents are swapped into place with a rename so that readers
+// (e.g. a concurrently reloading TLS server) never observe a partially
+// written certificate or key.
+func writeCertFilesLocked(certPath, keyPath string, certPEM, keyPEM []byte) error {
+	dir := filepath.Dir(certPath)
+	lockPath := filepath.Join(dir, ".bootstrap.lock")
+
+	lock, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open lock file %q: %w", lockPath, err)
+	}
+	defer lock.Close()
+
+	if err := syscall.Flock(int(lock.Fd()), syscall.LOCK_EX); err != nil {
+		return fmt.Errorf("failed to acquire lock on %q: %w", lockPath, err)
+	}
+	defer syscall.Flock(int(lock.Fd()), syscall.LOCK_UN)
+
+	if err := atomicWriteFile(certPath, certPEM, 0o644); err != nil {
+		return fmt.Errorf("failed to write certificate %q: %w", certPath, err)
+	}
+	if err := atomicWriteFile(keyPath, keyPEM, 0o600); err != nil {
+		return fmt.Errorf("failed to write key %q: %w", keyPath, err)
+	}
+
+	return nil
+}
+
+// atomicWriteFile writes data to a temp file in the same directory as path,
+// then renames it into place. Rename is atomic on the same filesystem, so
+// concurrent readers always see either the old or the new file, never a
+// truncated one.
+func atomicWriteFile(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmpPath := tmp.Name()
+
+	// Clean up the temp file on any path that doesn't end in a successful rename.
+	succeeded := false
+	defer func() {
+		if !succeeded {
+			os.Remove(tmpPath)
+		}
+	}()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to chmod temp file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temp file: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to rename temp file into place: %w", err)
+	}
+
+	succeeded = true
+	return nil
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2024 Canonical, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	secretCACertKey = "ca.crt"
+	secretCAKeyKey  = "ca.key"
+)
+
+// loadOrCreateCASecret returns the CA identity shared by all replicas,
+// creating it if this is the first replica to start. If two replicas race
+// to create the Secret, the loser re-reads the winner's Secret instead of
+// overwriting it, so every replica converges on a single CA.
+func loadOrCreateCASecret(ctx context.Context, client kubernetes.Interface, namespace, name, commonName string) (*caKeyPair, error) {
+	secrets := client.CoreV1().Secrets(namespace)
+
+	secret, err := secrets.Get(ctx, name, metav1.GetOptions{})
+	if err == nil {
+		return parseCA(secret.Data[secretCACertKey], secret.Data[secretCAKeyKey])
+	}
+	if !apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("failed to get CA secret %s/%s: %w", namespace, name, err)
+	}
+
+	ca, err := generateCA(commonName)
+	if err != nil {
+		return nil, err
+	}
+
+	secret = &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			secretCACertKey: ca.certPEM,
+			secretCAKeyKey:  ca.keyPEM,
+		},
+	}
+
+	_, err = secrets.Create(ctx, secret, metav1.CreateOptions{})
+	if err == nil {
+		return ca, nil
+	}
+	if !apierrors.IsAlreadyExists(err) {
+		return nil, fmt.Errorf("failed to create CA secret %s/%s: %w", namespace, name, err)
+	}
+
+	// Another replica won the race; use its CA instead of ours.
+	secret, err = secrets.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get CA secret %s/%s after losing create race: %w", namespace, name, err)
+	}
+
+	return parseCA(secret.Data[secretCACertKey], secret.Data[secretCAKeyKey])
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2024 Canonical, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bootstrap
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8stesting "k8s.io/client-go/testing"
+
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestLoadOrCreateCASecret_CreatesWhenMissing(t *testing.T) {
+	client := fake.NewSimpleClientset()
+
+	ca, err := loadOrCreateCASecret(context.Background(), client, "ns", "ca-secret", "test CA")
+	if err != nil {
+		t.Fatalf("loadOrCreateCASecret() returned error: %v", err)
+	}
+
+	secret, err := client.CoreV1().Secrets("ns").Get(context.Background(), "ca-secret", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("expected the CA Secret to have been created, but Get() returned error: %v", err)
+	}
+	if string(secret.Data[secretCACertKey]) != string(ca.certPEM) {
+		t.Error("created Secret's CA certificate doesn't match the returned caKeyPair's")
+	}
+}
+
+func TestLoadOrCreateCASecret_LoadsExisting(t *testing.T) {
+	existing, err := generateCA("existing CA")
+	if err != nil {
+		t.Fatalf("generateCA() returned error: %v", err)
+	}
+
+	client := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "ca-secret", Namespace: "ns"},
+		Data: map[string][]byte{
+			secretCACertKey: existing.certPEM,
+			secretCAKeyKey:  existing.keyPEM,
+		},
+	})
+
+	ca, err := loadOrCreateCASecret(context.Background(), client, "ns", "ca-secret", "should not be used")
+	if err != nil {
+		t.Fatalf("loadOrCreateCASecret() returned error: %v", err)
+	}
+
+	if ca.cert.Subject.CommonName != "existing CA" {
+		t.Errorf("loadOrCreateCASecret() returned a freshly generated CA instead of the existing one: CommonName = %q", ca.cert.Subject.CommonName)
+	}
+}
+
+// TestLoadOrCreateCASecret_CreateRace simulates a second replica losing the
+// race to create the CA Secret: its Create call returns AlreadyExists, and
+// it must fall back to the Secret the winner created rather than erroring
+// out or minting a second, divergent CA.
+func TestLoadOrCreateCASecret_CreateRace(t *testing.T) {
+	winner, err := generateCA("winner CA")
+	if err != nil {
+		t.Fatalf("generateCA() returned error: %v", err)
+	}
+
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "secrets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewAlreadyExists(schema.GroupResource{Resource: "secrets"}, "ca-secret")
+	})
+
+	// The first Get (before the Create race) reports NotFound; the second
+	// Get (after losing the race) returns the winner's Secret, simulating
+	// it having since been created by another replica.
+	getCalls := 0
+	client.PrependReactor("get", "secrets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		getCalls++
+		if getCalls == 1 {
+			return true, nil, apierrors.NewNotFound(schema.GroupResource{Resource: "secrets"}, "ca-secret")
+		}
+		return true, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "ca-secret", Namespace: "ns"},
+			Data: map[string][]byte{
+				secretCACertKey: winner.certPEM,
+				secretCAKeyKey:  winner.keyPEM,
+			},
+		}, nil
+	})
+
+	ca, err := loadOrCreateCASecret(context.Background(), client, "ns", "ca-secret", "loser CA")
+	if err != nil {
+		t.Fatalf("loadOrCreateCASecret() returned error: %v", err)
+	}
+
+	if ca.cert.Subject.CommonName != "winner CA" {
+		t.Errorf("loadOrCreateCASecret() did not converge on the winning CA: CommonName = %q, want %q", ca.cert.Subject.CommonName, "winner CA")
+	}
+}
+
+func TestLoadOrCreateCASecret_GetError(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("get", "secrets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewInternalError(context.DeadlineExceeded)
+	})
+
+	if _, err := loadOrCreateCASecret(context.Background(), client, "ns", "ca-secret", "test CA"); err == nil {
+		t.Error("loadOrCreateCASecret() returned no error for a non-NotFound Get failure")
+	}
+}
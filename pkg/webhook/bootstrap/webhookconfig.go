@@ -0,0 +1,85 @@
+/*
+Copyright 2024 Canonical, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bootstrap
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// reconcileWebhookCABundle updates the caBundle of every webhook entry in
+// the named MutatingWebhookConfiguration whose clientConfig.service points
+// at our in-cluster Service, so a cluster operator only has to manage the
+// rest of the configuration (rules, failurePolicy, etc.) while we own the
+// CA wiring. It's a no-op if the caBundle is already up to date.
+func reconcileWebhookCABundle(ctx context.Context, client kubernetes.Interface, webhookConfigName, serviceName, serviceNamespace string, caBundle []byte) error {
+	webhookConfigs := client.AdmissionregistrationV1().MutatingWebhookConfigurations()
+
+	config, err := webhookConfigs.Get(ctx, webhookConfigName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get MutatingWebhookConfiguration %q: %w", webhookConfigName, err)
+	}
+
+	changed := false
+	for i := range config.Webhooks {
+		svc := config.Webhooks[i].ClientConfig.Service
+		if svc == nil || svc.Name != serviceName || svc.Namespace != serviceNamespace {
+			continue
+		}
+
+		if bytes.Equal(config.Webhooks[i].ClientConfig.CABundle, caBundle) {
+			continue
+		}
+
+		config.Webhooks[i].ClientConfig.CABundle = caBundle
+		changed = true
+	}
+
+	if !changed {
+		return nil
+	}
+
+	if _, err := webhookConfigs.Update(ctx, config, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update MutatingWebhookConfiguration %q: %w", webhookConfigName, err)
+	}
+
+	return nil
+}
+
+// currentCABundle returns the caBundle currently set on the webhook entries
+// pointing at our Service, used by the readiness check to confirm the
+// in-memory CA and the cluster's view of it have converged.
+func currentCABundle(ctx context.Context, client kubernetes.Interface, webhookConfigName, serviceName, serviceNamespace string) ([]byte, error) {
+	config, err := client.AdmissionregistrationV1().MutatingWebhookConfigurations().Get(ctx, webhookConfigName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get MutatingWebhookConfiguration %q: %w", webhookConfigName, err)
+	}
+
+	for _, wh := range config.Webhooks {
+		svc := wh.ClientConfig.Service
+		if svc == nil || svc.Name != serviceName || svc.Namespace != serviceNamespace {
+			continue
+		}
+		return wh.ClientConfig.CABundle, nil
+	}
+
+	return nil, fmt.Errorf("no webhook entry in %q targets service %s/%s", webhookConfigName, serviceNamespace, serviceName)
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2024 Canonical, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	v1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Builder registers Admitters under HTTP paths and serves them as an
+// admission webhook. New mutations or validations are added with
+// builder.Register(path, admitter) - no changes to the HTTP plumbing are
+// needed.
+type Builder struct {
+	mux *http.ServeMux
+}
+
+// NewBuilder returns an empty Builder.
+func NewBuilder() *Builder {
+	return &Builder{mux: http.NewServeMux()}
+}
+
+// Register serves admitter's decisions at path and returns the Builder, so
+// registrations can be chained.
+func (b *Builder) Register(path string, admitter Admitter) *Builder {
+	b.mux.HandleFunc(path, serveAdmitter(path, admitter))
+	return b
+}
+
+// Handler returns the http.Handler serving every registered path.
+func (b *Builder) Handler() http.Handler {
+	return b.mux
+}
+
+// serveAdmitter adapts an Admitter to an http.HandlerFunc: decode the
+// AdmissionReview, run the Admitter, encode the response.
+func serveAdmitter(path string, admitter Admitter) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+		logger := slog.Default().With("URI", req.RequestURI)
+		logger.Info("Admitting request...")
+
+		review, err := deserializeRequest(req)
+		if err != nil {
+			logger.Error("Encountered error while deserializing.", "error", err)
+			admissionRequestsTotal.WithLabelValues(path, "unknown", "errored").Inc()
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		operation := string(review.Request.Operation)
+
+		response := admitter.Handle(req.Context(), Request{review.Request})
+		admissionDurationSeconds.WithLabelValues(path, operation).Observe(time.Since(start).Seconds())
+		recordPatchesEmitted(response.Patches)
+
+		admissionResponse, err := response.toAdmissionResponse(review.Request.UID)
+		if err != nil {
+			logger.Error("Encountered error while processing request.", "error", err)
+			admissionResponse = toV1AdmissionResponse(err)
+			admissionResponse.UID = review.Request.UID
+		}
+
+		result := "denied"
+		if admissionResponse.Allowed {
+			result = "allowed"
+		}
+		admissionRequestsTotal.WithLabelValues(path, operation, result).Inc()
+		logAdmissionAudit(logger, review.Request, admissionResponse, len(response.Patches))
+
+		resp := &v1.AdmissionReview{}
+		resp.SetGroupVersionKind(v1.SchemeGroupVersion.WithKind("AdmissionReview"))
+		resp.Response = admissionResponse
+
+		respBytes, err := json.Marshal(resp)
+		if err != nil {
+			logger.Error("Encountered error while marshaling response.", "error", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write(respBytes); err != nil {
+			logger.Error("Encountered error while writing response.", "error", err)
+		}
+	}
+}
+
+// toAdmissionResponse converts a Response into the wire AdmissionResponse,
+// marshaling Patches if present.
+func (r Response) toAdmissionResponse(uid types.UID) (*v1.AdmissionResponse, error) {
+	admissionResponse := &v1.AdmissionResponse{
+		UID:     uid,
+		Allowed: r.Allowed,
+		Result:  r.Result,
+	}
+
+	if len(r.Patches) == 0 {
+		return admissionResponse, nil
+	}
+
+	patchBytes, err := json.Marshal(r.Patches)
+	if err != nil {
+		return nil, err
+	}
+
+	pt := v1.PatchTypeJSONPatch
+	admissionResponse.PatchType = &pt
+	admissionResponse.Patch = patchBytes
+
+	return admissionResponse, nil
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2024 Canonical, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// defaultCertExpiryThreshold is how close to expiry a serving certificate
+// can get before readiness starts failing.
+const defaultCertExpiryThreshold = 24 * time.Hour
+
+// ReadinessChecker reports whether the webhook is ready to serve admission
+// traffic. Unlike ServeHealthz, which only reports that the process is up,
+// a ReadinessChecker can fail independently of the process, e.g. while a
+// certificate is being rotated.
+type ReadinessChecker interface {
+	Ready(ctx context.Context) error
+}
+
+// staticCertReadinessChecker is the ReadinessChecker used when the serving
+// certificate is loaded once from disk at startup (i.e. self-bootstrap is
+// disabled): it's ready as long as that certificate isn't about to expire.
+type staticCertReadinessChecker struct {
+	leaf      *x509.Certificate
+	threshold time.Duration
+}
+
+// NewStaticCertReadinessChecker returns a ReadinessChecker for a certificate
+// that was loaded once at startup and never rotates in-process.
+func NewStaticCertReadinessChecker(cert tls.Certificate) (ReadinessChecker, error) {
+	leaf := cert.Leaf
+	if leaf == nil {
+		parsed, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse certificate: %w", err)
+		}
+		leaf = parsed
+	}
+
+	return &staticCertReadinessChecker{leaf: leaf, threshold: defaultCertExpiryThreshold}, nil
+}
+
+func (s *staticCertReadinessChecker) Ready(_ context.Context) error {
+	if time.Until(s.leaf.NotAfter) < s.threshold {
+		return fmt.Errorf("certificate is within %s of expiring", s.threshold)
+	}
+	return nil
+}
+
+// ServeHealthz reports whether the process is up. It's deliberately cheap
+// and never fails: a stuck dependency (like a rotating certificate) should
+// take the Pod out of Service rotation via readiness, not restart it.
+func ServeHealthz(w http.ResponseWriter, _ *http.Request) {
+	slog.Debug("Healthy")
+	if _, err := w.Write([]byte("OK")); err != nil {
+		slog.Error("Encountered error while reporting health.", "error", err)
+	}
+}
+
+// ServeReadyz reports whether the webhook is ready to serve admission
+// traffic, per checker.
+func ServeReadyz(checker ReadinessChecker) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if err := checker.Ready(req.Context()); err != nil {
+			slog.Warn("Not ready.", "error", err)
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+
+		if _, err := w.Write([]byte("OK")); err != nil {
+			slog.Error("Encountered error while reporting readiness.", "error", err)
+		}
+	}
+}
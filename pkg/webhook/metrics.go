@@ -0,0 +1,72 @@
+/*
+Copyright 2024 Canonical, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"strings"
+
+	"github.com/appscode/jsonpatch"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	admissionRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pebble_webhook_admission_requests_total",
+		Help: "Total number of admission requests handled, by path, operation, and result.",
+	}, []string{"path", "operation", "result"})
+
+	admissionDurationSeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "pebble_webhook_admission_duration_seconds",
+		Help: "Time taken to handle an admission request, by path and operation.",
+	}, []string{"path", "operation"})
+
+	patchesEmittedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pebble_webhook_patches_emitted_total",
+		Help: "Total number of JSON patch operations emitted, by kind.",
+	}, []string{"kind"})
+
+	decodeErrorsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "pebble_webhook_decode_errors_total",
+		Help: "Total number of times an admission request's object failed to decode.",
+	})
+)
+
+// recordPatchesEmitted increments pebble_webhook_patches_emitted_total for
+// each patch operation, classified by classifyPatch.
+func recordPatchesEmitted(patches []jsonpatch.Operation) {
+	for _, patch := range patches {
+		patchesEmittedTotal.WithLabelValues(classifyPatch(patch)).Inc()
+	}
+}
+
+// classifyPatch buckets a patch operation into one of the kinds operators
+// care about: an empty-dir volume being added, a container's VolumeMount
+// pointing at it, or a container env var being added or replaced.
+func classifyPatch(patch jsonpatch.Operation) string {
+	switch {
+	case strings.Contains(patch.Path, "/volumes"), strings.Contains(patch.Path, "/volumeMounts"):
+		return "volume-add"
+	case strings.Contains(patch.Path, "/env"):
+		if patch.Operation == "replace" {
+			return "env-replace"
+		}
+		return "env-add"
+	default:
+		return "other"
+	}
+}
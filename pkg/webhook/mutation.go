@@ -17,14 +17,15 @@ limitations under the License.
 package webhook
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"path/filepath"
+	"strings"
 
-	"github.com/appscode/jsonpatch"
-	"k8s.io/api/admission/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
 )
 
 const (
@@ -33,53 +34,254 @@ const (
 	pebbleDefaultPath        string = "/var/lib/pebble/default"
 	pebbleWritableSubpath    string = "writable"
 
-	// Patch paths.
-	containerVolumeMountPath string = "/spec/containers/%d/volumeMounts/-"
-	containerEnvPath         string = "/spec/containers/%d/env%s"
-	podVolumePath            string = "/spec/volumes/-"
+	// ephemeralContainersSubResource is the admission subresource used for
+	// the only request type that can add ephemeral containers to a Pod.
+	ephemeralContainersSubResource string = "ephemeralcontainers"
+
+	// Subpath prefixes, so containers of different kinds with the same name
+	// (e.g. an init container and a regular container both named "app")
+	// never share a Pebble state dir.
+	mainContainerSubPathPrefix      string = "main"
+	initContainerSubPathPrefix      string = "init"
+	ephemeralContainerSubPathPrefix string = "eph"
+
+	annotationDomain                string = "pebble.rocks.canonical.com"
+	injectAnnotation                string = annotationDomain + "/inject"
+	pathAnnotation                  string = annotationDomain + "/path"
+	writableSubpathAnnotation       string = annotationDomain + "/writable-subpath"
+	containerAnnotationPrefix       string = annotationDomain + "/container."
+	containerInjectAnnotationSuffix string = ".inject"
+
+	// namespaceDefaultInjectLabel lets a cluster operator change the
+	// fallback inject decision for every Pod in a namespace that doesn't
+	// carry its own pebble.rocks.canonical.com/inject annotation.
+	namespaceDefaultInjectLabel string = annotationDomain + "/default-inject"
 )
 
-func getContainerEnvPatchOps(container corev1.Container, currentPath, mountPath string, containerIndex int) []jsonpatch.Operation {
-	envs := []corev1.EnvVar{
-		{
-			Name:  pebbleEnvVarName,
-			Value: mountPath,
-		},
-		{
-			Name:  pebbleEnvCopyOnceVarName,
-			Value: currentPath,
-		},
+// injectDecision is the outcome of evaluating the inject annotations/labels
+// for a Pod or container.
+type injectDecision int
+
+const (
+	// injectAuto falls through to the next, lower-precedence source of
+	// truth, and ultimately to the ReadOnlyRootFilesystem heuristic.
+	injectAuto injectDecision = iota
+	injectForce
+	injectSkip
+)
+
+func parseInjectDecision(value string) (injectDecision, error) {
+	switch value {
+	case "true":
+		return injectForce, nil
+	case "false":
+		return injectSkip, nil
+	case "auto", "":
+		return injectAuto, nil
+	default:
+		return injectAuto, fmt.Errorf("invalid value %q: must be one of \"true\", \"false\", \"auto\"", value)
 	}
+}
 
-	// If the container has no Env at all, we need to create it as well.
-	if container.Env == nil {
-		patchPath := fmt.Sprintf(containerEnvPath, containerIndex, "")
-		return []jsonpatch.Operation{jsonpatch.NewPatch("add", patchPath, envs)}
+// pebbleInjectConfig is the pod-level Pebble injection configuration parsed
+// from annotations, plus any per-container overrides.
+type pebbleInjectConfig struct {
+	podDecision        injectDecision
+	path               string
+	writableSubpath    string
+	containerDecisions map[string]injectDecision
+}
+
+func (cfg *pebbleInjectConfig) defaultPath() string {
+	if cfg.path != "" {
+		return cfg.path
 	}
+	return pebbleDefaultPath
+}
 
-	ops := []jsonpatch.Operation{}
+// shouldInject resolves the inject decision for a container, in order of
+// precedence: a per-container annotation, the pod-level annotation, the
+// namespace's default-inject label, and finally the
+// ReadOnlyRootFilesystem heuristic.
+func (cfg *pebbleInjectConfig) shouldInject(containerName string, heuristic bool, namespaceDefault injectDecision) bool {
+	if decision, ok := cfg.containerDecisions[containerName]; ok && decision != injectAuto {
+		return decision == injectForce
+	}
 
-	for _, env := range envs {
-		if index := findEnvVar(container, env.Name); index != -1 {
-			patchPath := fmt.Sprintf(containerEnvPath, containerIndex, fmt.Sprintf("/%d", index))
-			ops = append(ops, jsonpatch.NewPatch("replace", patchPath, env))
-			continue
+	if cfg.podDecision != injectAuto {
+		return cfg.podDecision == injectForce
+	}
+
+	if namespaceDefault != injectAuto {
+		return namespaceDefault == injectForce
+	}
+
+	return heuristic
+}
+
+// parsePebbleInjectConfig parses the pebble.rocks.canonical.com/* annotations
+// off pod. Malformed values are reported as errors rather than ignored, so a
+// typo results in an admission denial instead of a silent no-op.
+func parsePebbleInjectConfig(pod *corev1.Pod) (*pebbleInjectConfig, error) {
+	cfg := &pebbleInjectConfig{
+		podDecision:        injectAuto,
+		writableSubpath:    pebbleWritableSubpath,
+		containerDecisions: map[string]injectDecision{},
+	}
+
+	for key, value := range pod.Annotations {
+		switch {
+		case key == injectAnnotation:
+			decision, err := parseInjectDecision(value)
+			if err != nil {
+				return nil, fmt.Errorf("annotation %q: %w", key, err)
+			}
+			cfg.podDecision = decision
+
+		case key == pathAnnotation:
+			if value == "" {
+				return nil, fmt.Errorf("annotation %q must not be empty", key)
+			}
+			cfg.path = value
+
+		case key == writableSubpathAnnotation:
+			if value == "" {
+				return nil, fmt.Errorf("annotation %q must not be empty", key)
+			}
+			cfg.writableSubpath = value
+
+		default:
+			containerName, ok := parseContainerInjectAnnotation(key)
+			if !ok {
+				continue
+			}
+			decision, err := parseInjectDecision(value)
+			if err != nil {
+				return nil, fmt.Errorf("annotation %q: %w", key, err)
+			}
+			cfg.containerDecisions[containerName] = decision
 		}
+	}
+
+	return cfg, nil
+}
 
-		// The env var doesn't exist, add it.
-		patchPath := fmt.Sprintf(containerEnvPath, containerIndex, "/-")
-		ops = append(ops, jsonpatch.NewPatch("add", patchPath, env))
+// parseContainerInjectAnnotation extracts the container name out of a
+// pebble.rocks.canonical.com/container.<name>.inject annotation key.
+func parseContainerInjectAnnotation(key string) (string, bool) {
+	if !strings.HasPrefix(key, containerAnnotationPrefix) || !strings.HasSuffix(key, containerInjectAnnotationSuffix) {
+		return "", false
 	}
 
-	return ops
+	name := strings.TrimSuffix(strings.TrimPrefix(key, containerAnnotationPrefix), containerInjectAnnotationSuffix)
+	if name == "" {
+		return "", false
+	}
+
+	return name, true
 }
 
-func containerHasMountPath(container corev1.Container, path string) bool {
-	if container.VolumeMounts == nil {
-		return false
+// namespaceDefaultInject looks up the namespace's default-inject label, used
+// as the inject decision for Pods without their own pod-level annotation.
+func namespaceDefaultInject(ctx context.Context, client kubernetes.Interface, namespace string) (injectDecision, error) {
+	if client == nil || namespace == "" {
+		return injectAuto, nil
+	}
+
+	ns, err := client.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+	if err != nil {
+		return injectAuto, fmt.Errorf("failed to get namespace %q: %w", namespace, err)
+	}
+
+	value, ok := ns.Labels[namespaceDefaultInjectLabel]
+	if !ok {
+		return injectAuto, nil
+	}
+
+	decision, err := parseInjectDecision(value)
+	if err != nil {
+		return injectAuto, fmt.Errorf("namespace %q label %q: %w", namespace, namespaceDefaultInjectLabel, err)
+	}
+
+	return decision, nil
+}
+
+// NewAddPebbleMountMutator returns a Mutator that adds a writable empty-dir
+// volume (and the env vars pointing Pebble at it) to any container, init
+// container, or ephemeral container in the Pod that needs one, honoring the
+// pebble.rocks.canonical.com/* annotations and the owning namespace's
+// default-inject label. client is used to look up the namespace's label; a
+// nil client disables that lookup (the namespace default is then always
+// "auto").
+func NewAddPebbleMountMutator(client kubernetes.Interface) *Mutator {
+	return &Mutator{
+		Decode: decodePod,
+		Mutate: func(ctx context.Context, obj runtime.Object, req Request) error {
+			pod, ok := obj.(*corev1.Pod)
+			if !ok {
+				return fmt.Errorf("expected a *corev1.Pod, got %T", obj)
+			}
+
+			cfg, err := parsePebbleInjectConfig(pod)
+			if err != nil {
+				return err
+			}
+
+			namespaceDefault, err := namespaceDefaultInject(ctx, client, req.Namespace)
+			if err != nil {
+				return err
+			}
+
+			addPebbleVolumeMounts(pod, cfg, namespaceDefault, req.SubResource == ephemeralContainersSubResource)
+			return nil
+		},
+	}
+}
+
+// ValidatePebbleMountValidator rejects Pods whose Pebble-related annotations
+// or env vars don't make sense. It's registered at /validate-pebble-mount in
+// main.go.
+var ValidatePebbleMountValidator = &Validator{
+	Decode:   decodePod,
+	Validate: validatePebbleMount,
+}
+
+// podResource is the only Resource decodePod accepts; a webhook miswired to
+// any other resource is rejected instead of being decoded into a zero-value
+// Pod.
+var podResource = metav1.GroupVersionResource{Group: "", Version: "v1", Resource: "pods"}
+
+func decodePod(req Request) (runtime.Object, error) {
+	if req.Resource != podResource {
+		return nil, fmt.Errorf("expected resource to be %q, got %q", podResource, req.Resource)
+	}
+
+	pod := &corev1.Pod{}
+	if _, _, err := deserializer.Decode(req.Object.Raw, nil, pod); err != nil {
+		return nil, fmt.Errorf("request could not be decoded: %w", err)
+	}
+	return pod, nil
+}
+
+func setPebbleEnv(env []corev1.EnvVar, currentPath, mountPath string) []corev1.EnvVar {
+	updates := []corev1.EnvVar{
+		{Name: pebbleEnvVarName, Value: mountPath},
+		{Name: pebbleEnvCopyOnceVarName, Value: currentPath},
+	}
+
+	for _, update := range updates {
+		if index := findEnvVar(env, update.Name); index != -1 {
+			env[index] = update
+			continue
+		}
+		env = append(env, update)
 	}
 
-	for _, mount := range container.VolumeMounts {
+	return env
+}
+
+func hasMountPath(mounts []corev1.VolumeMount, path string) bool {
+	for _, mount := range mounts {
 		if mount.MountPath == path {
 			return true
 		}
@@ -88,13 +290,9 @@ func containerHasMountPath(container corev1.Container, path string) bool {
 	return false
 }
 
-func findEnvVar(container corev1.Container, varName string) int {
-	if container.Env == nil {
-		return -1
-	}
-
-	for i, env := range container.Env {
-		if env.Name == varName {
+func findEnvVar(env []corev1.EnvVar, varName string) int {
+	for i, e := range env {
+		if e.Name == varName {
 			return i
 		}
 	}
@@ -102,18 +300,17 @@ func findEnvVar(container corev1.Container, varName string) int {
 	return -1
 }
 
-// Get the configured $PEBBLE path env variable, if any. If not, return the default $PEBBLE path.
-func getContainerPebblePath(container corev1.Container) string {
-	if index := findEnvVar(container, pebbleEnvVarName); index != -1 {
-		return container.Env[index].Value
+// Get the configured $PEBBLE path env variable, if any. If not, return defaultPath.
+func getPebblePath(env []corev1.EnvVar, defaultPath string) string {
+	if index := findEnvVar(env, pebbleEnvVarName); index != -1 {
+		return env[index].Value
 	}
 
-	return pebbleDefaultPath
+	return defaultPath
 }
 
-func containerNeedsPebbleVolume(container corev1.Container) bool {
+func needsPebbleVolume(secContext *corev1.SecurityContext) bool {
 	// By default, Containers do not have read-only Root FS.
-	secContext := container.SecurityContext
 	if secContext == nil || secContext.ReadOnlyRootFilesystem == nil {
 		return false
 	}
@@ -121,104 +318,194 @@ func containerNeedsPebbleVolume(container corev1.Container) bool {
 	return *secContext.ReadOnlyRootFilesystem
 }
 
-// Returns the Pod JSON patches needed by any rock images in it.
-// Pebble needs to be able to write its state. Thus, for containers with read-only root FS,
-// the containers need an empty dir volume mount in their $PEBBLE folder.
-func getPebbleVolumeMountPatches(pod *corev1.Pod) []jsonpatch.Operation {
-	patches := []jsonpatch.Operation{}
-
-	for i, container := range pod.Spec.Containers {
-		// We don't need to mount a volume if the root FS is not read-only.
-		if !containerNeedsPebbleVolume(container) {
-			continue
-		}
-
-		// Make sure that there's no volume already targeting the $PEBBLE path.
-		// If there is, we'll let the user handle it.
-		// We might want to check if the user also defined the PEBBLE_COPY_ONCE env variable
-		// in this case, as the $PEBBLE folder won't have the layers folder needed by Pebble.
-		pebblePath := getContainerPebblePath(container)
-		if containerHasMountPath(container, pebblePath) {
-			continue
-		}
+// mutateContainer mutates a regular or init container in place, adding a
+// writable empty-dir VolumeMount in its $PEBBLE folder if it needs one.
+// Pebble needs to be able to write its state. Thus, for containers with
+// read-only root FS, the containers need an empty dir volume mount in their
+// $PEBBLE folder. Returns whether pod.Spec.Volumes needs the shared
+// "pebble-dir" volume added.
+func mutateContainer(container *corev1.Container, subPathPrefix string, cfg *pebbleInjectConfig, namespaceDefault injectDecision) bool {
+	if !cfg.shouldInject(container.Name, needsPebbleVolume(container.SecurityContext), namespaceDefault) {
+		return false
+	}
 
-		// The layers folder exists in the $PEBBLE path. This means we can't mount there, as
-		// that will cause the layers folder to no longer exists in the $PEBBLE path.
-		// Instead, we should mount in a subfolder, and set the $PEBBLE and $PEBBLE_READ_ONCE env vars.
-		mountPath := filepath.Join(pebblePath, pebbleWritableSubpath)
+	// Make sure that there's no volume already targeting the $PEBBLE path.
+	// If there is, we'll let the user handle it.
+	// We might want to check if the user also defined the PEBBLE_COPY_ONCE env variable
+	// in this case, as the $PEBBLE folder won't have the layers folder needed by Pebble.
+	pebblePath := getPebblePath(container.Env, cfg.defaultPath())
+	if hasMountPath(container.VolumeMounts, pebblePath) {
+		return false
+	}
 
-		// Add volume patch to the current container.
-		// The subpath is required if there are multiple rocks in the same Pod.
-		patches = append(patches, jsonpatch.NewPatch("add", fmt.Sprintf(containerVolumeMountPath, i),
-			corev1.VolumeMount{
-				Name:      "pebble-dir",
-				MountPath: mountPath,
-				SubPath:   container.Name,
-			},
-		))
+	// The layers folder exists in the $PEBBLE path. This means we can't mount there, as
+	// that will cause the layers folder to no longer exists in the $PEBBLE path.
+	// Instead, we should mount in a subfolder, and set the $PEBBLE and $PEBBLE_COPY_ONCE env vars.
+	mountPath := filepath.Join(pebblePath, cfg.writableSubpath)
+
+	// Add the volume mount to the current container. The subpath is
+	// required if there are multiple rocks in the same Pod: if we have
+	// multiple rocks with the same $PEBBLE path, they'd end up using the
+	// same socket and state files otherwise.
+	container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+		Name:      "pebble-dir",
+		MountPath: mountPath,
+		SubPath:   subPathPrefix + "-" + container.Name,
+	})
+
+	container.Env = setPebbleEnv(container.Env, pebblePath, mountPath)
+	return true
+}
 
-		// We're adding the same volume mount to all the containers in the Pod.
-		// If we have multiple rocks in the same Pod, and they have the same $PEBBLE path,
-		// they'd end up using the same socket and state files. We have to prevent that.
-		patches = append(patches, getContainerEnvPatchOps(container, pebblePath, mountPath, i)...)
+// mutateEphemeralContainer mutates an ephemeral container in place. Requests
+// on the ephemeralcontainers admission subresource don't allow adding new Pod
+// volumes or VolumeMounts - only the container's Env can be changed. So when
+// allowVolumeMount is false, an ephemeral container that needs injecting
+// still gets PEBBLE/PEBBLE_COPY_ONCE pointed at a writable subpath, but not
+// the VolumeMount or pod.Spec.Volumes entry backing it. When
+// allowVolumeMount is true it gets an "eph-" prefixed VolumeMount like any
+// other container. Returns whether pod.Spec.Volumes needs the shared
+// "pebble-dir" volume added.
+func mutateEphemeralContainer(container *corev1.EphemeralContainerCommon, allowVolumeMount bool, cfg *pebbleInjectConfig, namespaceDefault injectDecision) bool {
+	if !cfg.shouldInject(container.Name, needsPebbleVolume(container.SecurityContext), namespaceDefault) {
+		return false
 	}
 
-	// If we don't have any volume mounts, we don't need to add any volume.
-	if len(patches) == 0 {
-		return patches
+	pebblePath := getPebblePath(container.Env, cfg.defaultPath())
+	if hasMountPath(container.VolumeMounts, pebblePath) {
+		return false
 	}
 
-	// Add patch for Pebble volume.
-	patches = append(patches, jsonpatch.NewPatch("add", podVolumePath,
-		corev1.Volume{
-			Name:         "pebble-dir",
-			VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
-		},
-	))
+	mountPath := filepath.Join(pebblePath, cfg.writableSubpath)
+
+	needsVolume := false
+	if allowVolumeMount {
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+			Name:      "pebble-dir",
+			MountPath: mountPath,
+			SubPath:   ephemeralContainerSubPathPrefix + "-" + container.Name,
+		})
+		needsVolume = true
+	}
 
-	return patches
+	container.Env = setPebbleEnv(container.Env, pebblePath, mountPath)
+	return needsVolume
 }
 
-func getPod(ar *v1.AdmissionReview) (*corev1.Pod, error) {
-	pod := corev1.Pod{}
+// addPebbleVolumeMounts mutates pod in place, adding a writable empty-dir
+// volume to any container, init container, or ephemeral container that
+// needs one per cfg and namespaceDefault. ephemeralSubResource is true when
+// req targets the ephemeralcontainers subresource, in which case ephemeral
+// containers can only have their env vars updated - see
+// mutateEphemeralContainer.
+func addPebbleVolumeMounts(pod *corev1.Pod, cfg *pebbleInjectConfig, namespaceDefault injectDecision, ephemeralSubResource bool) {
+	needsVolume := false
+
+	for i := range pod.Spec.Containers {
+		if mutateContainer(&pod.Spec.Containers[i], mainContainerSubPathPrefix, cfg, namespaceDefault) {
+			needsVolume = true
+		}
+	}
 
-	if _, _, err := deserializer.Decode(ar.Request.Object.Raw, nil, &pod); err != nil {
-		return nil, fmt.Errorf("request could not be decoded: %w", err)
+	for i := range pod.Spec.InitContainers {
+		if mutateContainer(&pod.Spec.InitContainers[i], initContainerSubPathPrefix, cfg, namespaceDefault) {
+			needsVolume = true
+		}
+	}
+
+	for i := range pod.Spec.EphemeralContainers {
+		container := &pod.Spec.EphemeralContainers[i].EphemeralContainerCommon
+		if mutateEphemeralContainer(container, !ephemeralSubResource, cfg, namespaceDefault) {
+			needsVolume = true
+		}
+	}
+
+	// If we don't have any volume mounts, we don't need to add any volume.
+	if !needsVolume {
+		return
 	}
 
-	return &pod, nil
+	pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+		Name:         "pebble-dir",
+		VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+	})
 }
 
-// Check if the given container has the given mount path.
-// Add an empty dir volume for Pebble to store its state in.
-func addPebbleMountMutation(ar *v1.AdmissionReview) (*v1.AdmissionResponse, error) {
-	podResource := metav1.GroupVersionResource{Group: "", Version: "v1", Resource: "pods"}
-	if ar.Request.Resource != podResource {
-		return nil, fmt.Errorf("expected resource to be: '%s', actual: '%s'", podResource, ar.Request.Resource)
+// validatePebbleMount rejects Pods whose Pebble configuration can't be
+// satisfied: a user-supplied $PEBBLE path that collides with an existing
+// non-empty-dir volume mount, or a PEBBLE_COPY_ONCE that doesn't follow the
+// rock image's layers folder convention of living under the Pod's effective
+// Pebble path (pebbleDefaultPath, unless overridden by the
+// pebble.rocks.canonical.com/path annotation).
+func validatePebbleMount(_ context.Context, obj runtime.Object) error {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return fmt.Errorf("expected a *corev1.Pod, got %T", obj)
 	}
 
-	pod, err := getPod(ar)
+	cfg, err := parsePebbleInjectConfig(pod)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get pod: %w", err)
+		return err
 	}
+	layersPath := cfg.defaultPath()
 
-	reviewResponse := v1.AdmissionResponse{}
-	reviewResponse.Allowed = true
+	for _, container := range pod.Spec.Containers {
+		if err := validateContainerPebbleMount(pod, container.Env, container.VolumeMounts, layersPath); err != nil {
+			return fmt.Errorf("container %q: %w", container.Name, err)
+		}
+	}
 
-	patches := getPebbleVolumeMountPatches(pod)
-	if len(patches) == 0 {
-		// no mounts were necessary, so we don't need to change anything about the Pod.
-		return &reviewResponse, nil
+	for _, container := range pod.Spec.InitContainers {
+		if err := validateContainerPebbleMount(pod, container.Env, container.VolumeMounts, layersPath); err != nil {
+			return fmt.Errorf("init container %q: %w", container.Name, err)
+		}
 	}
 
-	patchBytes, err := json.Marshal(patches)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal patches: %w", err)
+	for _, container := range pod.Spec.EphemeralContainers {
+		if err := validateContainerPebbleMount(pod, container.Env, container.VolumeMounts, layersPath); err != nil {
+			return fmt.Errorf("ephemeral container %q: %w", container.Name, err)
+		}
 	}
 
-	pt := v1.PatchTypeJSONPatch
-	reviewResponse.PatchType = &pt
-	reviewResponse.Patch = patchBytes
+	return nil
+}
+
+// validateContainerPebbleMount validates a single container's (or init/
+// ephemeral container's) Pebble-related env vars against the volume mounts
+// available to it. layersPath is the Pod's effective Pebble path, i.e. where
+// the rock's layers folder is expected to live.
+func validateContainerPebbleMount(pod *corev1.Pod, env []corev1.EnvVar, volumeMounts []corev1.VolumeMount, layersPath string) error {
+	if index := findEnvVar(env, pebbleEnvVarName); index != -1 {
+		pebblePath := env[index].Value
+		if volume, ok := podVolumeAtMountPath(pod, volumeMounts, pebblePath); ok && volume.EmptyDir == nil {
+			return fmt.Errorf("$PEBBLE path %q is mounted from volume %q, which is not an emptyDir", pebblePath, volume.Name)
+		}
+	}
+
+	if index := findEnvVar(env, pebbleEnvCopyOnceVarName); index != -1 {
+		copyOncePath := env[index].Value
+		if copyOncePath != layersPath {
+			return fmt.Errorf("%s %q does not follow the rock layers folder convention (%q)", pebbleEnvCopyOnceVarName, copyOncePath, layersPath)
+		}
+	}
+
+	return nil
+}
+
+// podVolumeAtMountPath returns the Volume backing a VolumeMount at mountPath
+// within volumeMounts, if any.
+func podVolumeAtMountPath(pod *corev1.Pod, volumeMounts []corev1.VolumeMount, mountPath string) (corev1.Volume, bool) {
+	for _, mount := range volumeMounts {
+		if mount.MountPath != mountPath {
+			continue
+		}
+
+		for _, volume := range pod.Spec.Volumes {
+			if volume.Name == mount.Name {
+				return volume, true
+			}
+		}
+	}
 
-	return &reviewResponse, nil
+	return corev1.Volume{}, false
 }
@@ -0,0 +1,173 @@
+/*
+Copyright 2024 Canonical, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParsePebbleInjectConfig(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		wantErr     bool
+		check       func(t *testing.T, cfg *pebbleInjectConfig)
+	}{
+		{
+			name:        "no annotations defaults to auto",
+			annotations: nil,
+			check: func(t *testing.T, cfg *pebbleInjectConfig) {
+				if cfg.podDecision != injectAuto {
+					t.Errorf("podDecision = %v, want injectAuto", cfg.podDecision)
+				}
+				if cfg.path != "" {
+					t.Errorf("path = %q, want empty", cfg.path)
+				}
+				if cfg.writableSubpath != pebbleWritableSubpath {
+					t.Errorf("writableSubpath = %q, want %q", cfg.writableSubpath, pebbleWritableSubpath)
+				}
+			},
+		},
+		{
+			name:        "pod-level inject true",
+			annotations: map[string]string{injectAnnotation: "true"},
+			check: func(t *testing.T, cfg *pebbleInjectConfig) {
+				if cfg.podDecision != injectForce {
+					t.Errorf("podDecision = %v, want injectForce", cfg.podDecision)
+				}
+			},
+		},
+		{
+			name:        "invalid pod-level inject value",
+			annotations: map[string]string{injectAnnotation: "yes"},
+			wantErr:     true,
+		},
+		{
+			name:        "empty path annotation is rejected",
+			annotations: map[string]string{pathAnnotation: ""},
+			wantErr:     true,
+		},
+		{
+			name:        "custom path annotation",
+			annotations: map[string]string{pathAnnotation: "/custom/path"},
+			check: func(t *testing.T, cfg *pebbleInjectConfig) {
+				if cfg.defaultPath() != "/custom/path" {
+					t.Errorf("defaultPath() = %q, want %q", cfg.defaultPath(), "/custom/path")
+				}
+			},
+		},
+		{
+			name:        "per-container inject annotation",
+			annotations: map[string]string{containerAnnotationPrefix + "app" + containerInjectAnnotationSuffix: "false"},
+			check: func(t *testing.T, cfg *pebbleInjectConfig) {
+				decision, ok := cfg.containerDecisions["app"]
+				if !ok {
+					t.Fatal("containerDecisions[\"app\"] not set")
+				}
+				if decision != injectSkip {
+					t.Errorf("containerDecisions[\"app\"] = %v, want injectSkip", decision)
+				}
+			},
+		},
+		{
+			name:        "invalid per-container inject annotation",
+			annotations: map[string]string{containerAnnotationPrefix + "app" + containerInjectAnnotationSuffix: "maybe"},
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: tt.annotations}}
+
+			cfg, err := parsePebbleInjectConfig(pod)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parsePebbleInjectConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if tt.check != nil {
+				tt.check(t, cfg)
+			}
+		})
+	}
+}
+
+func TestShouldInject(t *testing.T) {
+	tests := []struct {
+		name             string
+		cfg              pebbleInjectConfig
+		containerName    string
+		heuristic        bool
+		namespaceDefault injectDecision
+		want             bool
+	}{
+		{
+			name:      "falls back to heuristic when nothing else is set",
+			cfg:       pebbleInjectConfig{podDecision: injectAuto},
+			heuristic: true,
+			want:      true,
+		},
+		{
+			name:             "namespace default overrides heuristic",
+			cfg:              pebbleInjectConfig{podDecision: injectAuto},
+			heuristic:        true,
+			namespaceDefault: injectSkip,
+			want:             false,
+		},
+		{
+			name:             "pod-level overrides namespace default",
+			cfg:              pebbleInjectConfig{podDecision: injectForce},
+			heuristic:        false,
+			namespaceDefault: injectSkip,
+			want:             true,
+		},
+		{
+			name: "per-container overrides pod-level",
+			cfg: pebbleInjectConfig{
+				podDecision:        injectSkip,
+				containerDecisions: map[string]injectDecision{"app": injectForce},
+			},
+			containerName: "app",
+			heuristic:     false,
+			want:          true,
+		},
+		{
+			name: "per-container auto falls through to pod-level",
+			cfg: pebbleInjectConfig{
+				podDecision:        injectForce,
+				containerDecisions: map[string]injectDecision{"app": injectAuto},
+			},
+			containerName: "app",
+			heuristic:     false,
+			want:          true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.cfg.shouldInject(tt.containerName, tt.heuristic, tt.namespaceDefault)
+			if got != tt.want {
+				t.Errorf("shouldInject() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
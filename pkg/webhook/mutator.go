@@ -0,0 +1,75 @@
+/*
+Copyright 2024 Canonical, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/appscode/jsonpatch"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// MutateFunc mutates obj in place. Handlers implement this instead of
+// hand-constructing jsonpatch.Operation values; Mutator diffs obj before
+// and after MutateFunc runs and synthesizes the patch automatically. req is
+// the admission request obj was decoded from, so handlers can branch on
+// fields like req.SubResource.
+type MutateFunc func(ctx context.Context, obj runtime.Object, req Request) error
+
+// Mutator is an Admitter that decodes the request object, runs MutateFunc
+// against a copy of it, and returns the JSON patch between the original and
+// the mutated copy.
+type Mutator struct {
+	// Decode turns the request's raw object into the concrete type MutateFunc
+	// expects, e.g. a *corev1.Pod.
+	Decode Decoder
+
+	// Mutate applies the desired changes to the decoded object in place.
+	Mutate MutateFunc
+}
+
+func (m *Mutator) Handle(ctx context.Context, req Request) Response {
+	obj, err := m.Decode(req)
+	if err != nil {
+		decodeErrorsTotal.Inc()
+		return errored(fmt.Errorf("failed to decode object: %w", err))
+	}
+
+	original, err := json.Marshal(obj)
+	if err != nil {
+		return errored(fmt.Errorf("failed to marshal original object: %w", err))
+	}
+
+	mutated := obj.DeepCopyObject()
+	if err := m.Mutate(ctx, mutated, req); err != nil {
+		return errored(fmt.Errorf("failed to mutate object: %w", err))
+	}
+
+	mutatedBytes, err := json.Marshal(mutated)
+	if err != nil {
+		return errored(fmt.Errorf("failed to marshal mutated object: %w", err))
+	}
+
+	patch, err := jsonpatch.CreatePatch(original, mutatedBytes)
+	if err != nil {
+		return errored(fmt.Errorf("failed to compute patch: %w", err))
+	}
+
+	return Response{Allowed: true, Patches: patch}
+}
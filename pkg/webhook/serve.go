@@ -18,9 +18,7 @@ package webhook
 
 import (
 	"bytes"
-	"encoding/json"
 	"fmt"
-	"log/slog"
 	"net/http"
 
 	v1 "k8s.io/api/admission/v1"
@@ -67,46 +65,3 @@ func deserializeRequest(req *http.Request) (*v1.AdmissionReview, error) {
 
 	return &requestedReview, nil
 }
-
-func ServeAddPebbleMount(w http.ResponseWriter, req *http.Request) {
-	logger := slog.Default().With("URI", req.RequestURI)
-	logger.Info("Mutating Pod...")
-
-	request, err := deserializeRequest(req)
-	if err != nil {
-		logger.Error("Encountered error while deserializing.", "error", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	}
-
-	response, err := addPebbleMountMutation(request)
-	if err != nil {
-		logger.Error("Encountered error while processing request.", "error", err)
-		response = toV1AdmissionResponse(err)
-	}
-
-	resp := &v1.AdmissionReview{}
-	resp.SetGroupVersionKind(v1.SchemeGroupVersion.WithKind("AdmissionReview"))
-	resp.Response = response
-	resp.Response.UID = request.Request.UID
-
-	logger.Info("Sending response.", "response", resp)
-	respBytes, err := json.Marshal(resp)
-	if err != nil {
-		logger.Error("Encountered error while marshaling response.", "error", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	if _, err := w.Write(respBytes); err != nil {
-		logger.Error("Encountered error while writing response.", "error", err)
-	}
-}
-
-func ServeHealthz(w http.ResponseWriter, _ *http.Request) {
-	slog.Debug("Healthy")
-	if _, err := w.Write([]byte("OK")); err != nil {
-		slog.Error("Encountered error while reporting health.", "error", err)
-	}
-}
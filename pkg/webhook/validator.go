@@ -0,0 +1,54 @@
+/*
+Copyright 2024 Canonical, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// ValidateFunc inspects obj and returns an error describing why it should
+// be rejected, or nil to allow it.
+type ValidateFunc func(ctx context.Context, obj runtime.Object) error
+
+// Validator is an Admitter that decodes the request object and runs
+// ValidateFunc against it, denying the request if it returns an error.
+type Validator struct {
+	// Decode turns the request's raw object into the concrete type
+	// ValidateFunc expects, e.g. a *corev1.Pod.
+	Decode Decoder
+
+	// Validate inspects the decoded object and returns an error if it
+	// should be rejected.
+	Validate ValidateFunc
+}
+
+func (v *Validator) Handle(ctx context.Context, req Request) Response {
+	obj, err := v.Decode(req)
+	if err != nil {
+		decodeErrorsTotal.Inc()
+		return errored(fmt.Errorf("failed to decode object: %w", err))
+	}
+
+	if err := v.Validate(ctx, obj); err != nil {
+		return Denied(err.Error())
+	}
+
+	return Allowed()
+}